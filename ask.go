@@ -0,0 +1,355 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Values by default
+var (
+	AskCursor       = "> " // String placed before of the highlighted choice
+	AskMarkChecked  = "[x] "
+	AskMarkUnchecked = "[ ] "
+)
+
+// === Select
+// ===
+
+// AskSelect prints the prompt and a scrollable list of choices, letting the
+// user move a highlighted cursor over them with the arrow keys and filter
+// with "/". It returns the chosen string.
+//
+// When raw mode can not be enabled (e.g. stdin is not a TTY) it falls back
+// to ReadChoice so the question can still be answered from a script or pipe.
+func (q *Question) AskSelect(prompt string, choices []string) (answer string, err error) {
+	if err = tty.RawMode(); err != nil {
+		return q.ReadChoice(prompt, choices)
+	}
+	defer q.RestoreTerm()
+
+	cursor := 0
+	filter := ""
+	q.selectLines = 0
+
+	for {
+		visible := filterChoices(choices, filter)
+		if len(visible) == 0 {
+			visible = choices
+			filter = ""
+		}
+		if cursor >= len(visible) {
+			cursor = len(visible) - 1
+		}
+
+		q.printSelect(prompt, visible, cursor, filter, nil)
+
+		key, err := readKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyUp:
+			if cursor > 0 {
+				cursor--
+			}
+		case keyDown:
+			if cursor < len(visible)-1 {
+				cursor++
+			}
+		case keyEnter:
+			return visible[cursor], nil
+		case keySlash:
+			filter, err = q.readFilter(filter)
+			if err != nil {
+				return "", err
+			}
+			cursor = 0
+		}
+	}
+	return
+}
+
+// AskMultiSelect is like AskSelect but lets the user toggle any number of
+// choices with Space before confirming with Enter.
+func (q *Question) AskMultiSelect(prompt string, choices []string) (answer []string, err error) {
+	if err = tty.RawMode(); err != nil {
+		return q.readMultiChoice(prompt, choices)
+	}
+	defer q.RestoreTerm()
+
+	cursor := 0
+	filter := ""
+	checked := make(map[string]bool)
+	q.selectLines = 0
+
+	for {
+		visible := filterChoices(choices, filter)
+		if len(visible) == 0 {
+			visible = choices
+			filter = ""
+		}
+		if cursor >= len(visible) {
+			cursor = len(visible) - 1
+		}
+
+		q.printSelect(prompt, visible, cursor, filter, checked)
+
+		key, err := readKey()
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case keyUp:
+			if cursor > 0 {
+				cursor--
+			}
+		case keyDown:
+			if cursor < len(visible)-1 {
+				cursor++
+			}
+		case keySpace:
+			checked[visible[cursor]] = !checked[visible[cursor]]
+		case keyEnter:
+			for _, c := range choices {
+				if checked[c] {
+					answer = append(answer, c)
+				}
+			}
+			return answer, nil
+		case keySlash:
+			filter, err = q.readFilter(filter)
+			if err != nil {
+				return nil, err
+			}
+			cursor = 0
+		}
+	}
+	return
+}
+
+// printSelect redraws the prompt and the list of visible choices in place,
+// putting the cursor mark and, when checked is not nil, the "[x]"/"[ ]"
+// mark. It moves the cursor back up over whatever the previous call
+// printed and clears to the end of the screen first, so each keypress
+// updates the list instead of scrolling the terminal.
+func (q *Question) printSelect(prompt string, visible []string, cursor int, filter string, checked map[string]bool) {
+	if q.selectLines > 0 {
+		fmt.Fprintf(output, "\033[%dA\033[J", q.selectLines)
+	}
+
+	fmt.Fprintf(output, "%s%s", QuestionPrefix, prompt)
+	if filter != "" {
+		fmt.Fprintf(output, " /%s", filter)
+	}
+	fmt.Fprint(output, "\r\n")
+	lines := 1
+
+	for i, choice := range visible {
+		mark := ""
+		if checked != nil {
+			if checked[choice] {
+				mark = AskMarkChecked
+			} else {
+				mark = AskMarkUnchecked
+			}
+		}
+
+		if i == cursor {
+			fmt.Fprintf(output, "%s%s%s%s%s\r\n", AskCursor, mark, setBold, choice, setOff)
+		} else {
+			fmt.Fprintf(output, "%s%s%s\r\n", strings.Repeat(" ", len(AskCursor)), mark, choice)
+		}
+		lines++
+	}
+
+	q.selectLines = lines
+}
+
+// readFilter reads a line of filter text entered after "/", ended by Enter.
+func (q *Question) readFilter(filter string) (string, error) {
+	line := q.getLine("filter", "", _DEFAULT_NO)
+	return line.Read()
+}
+
+// readMultiChoice is the bufio fallback used by AskMultiSelect when the
+// terminal can not be put into raw mode. It returns the comma separated
+// items as typed, or nil for an empty answer; the items are not checked
+// against choices.
+func (q *Question) readMultiChoice(prompt string, choices []string) ([]string, error) {
+	line := q.getLine(prompt+" (comma separated)", strings.Join(choices, ","), _DEFAULT_MULTIPLE)
+	answer, err := line.Read()
+	if err != nil {
+		return nil, err
+	}
+	if answer == "" {
+		return nil, nil
+	}
+	return strings.Split(answer, ","), nil
+}
+
+// filterChoices returns the choices containing substr.
+func filterChoices(choices []string, substr string) []string {
+	if substr == "" {
+		return choices
+	}
+
+	var out []string
+	for _, c := range choices {
+		if strings.Contains(c, substr) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// === Confirm, Password, Input, Editor
+// ===
+
+// AskConfirm is a thin wrapper around ReadBool kept for naming symmetry with
+// the other Ask* methods.
+func (q *Question) AskConfirm(prompt string, defaultAnswer bool) (bool, error) {
+	return q.ReadBool(prompt, defaultAnswer)
+}
+
+// AskInput is a thin wrapper around ReadString kept for naming symmetry with
+// the other Ask* methods.
+func (q *Question) AskInput(prompt string) (string, error) {
+	return q.ReadString(prompt)
+}
+
+// AskPassword prompts for a line read in no-echo mode, so the typed
+// characters never reach the terminal.
+func (q *Question) AskPassword(prompt string) (answer string, err error) {
+	line := q.getLine(prompt, "", _DEFAULT_NO)
+	line.SetEcho(EchoNone)
+
+	for {
+		answer, err = line.Read()
+		if err != nil {
+			return "", err
+		}
+		if answer != "" {
+			return
+		}
+	}
+	return
+}
+
+// AskEditor spawns $EDITOR (falling back to "vi") on a temporary file,
+// waits for it to exit and returns the file's contents. When stdin is not
+// a TTY -- a pipe or a script feeding input -- spawning an interactive
+// editor against it would hang or fail, so AskEditor instead reads the
+// answer as plain lines from stdin until EOF.
+func (q *Question) AskEditor(prompt string) (answer string, err error) {
+	if !tty.IsTerminal() {
+		return q.readEditorFallback(prompt)
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := ioutil.TempFile("", "linoise")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	if prompt != "" {
+		fmt.Fprintf(output, "%s%s\r\n", QuestionPrefix, prompt)
+	}
+
+	cmd := exec.Command(editor, name)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// readEditorFallback reads the answer as plain lines from stdin until EOF,
+// used by AskEditor when stdin is not a TTY.
+func (q *Question) readEditorFallback(prompt string) (string, error) {
+	if prompt != "" {
+		fmt.Fprintf(output, "%s%s\r\n", QuestionPrefix, prompt)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return strings.Join(lines, "\n"), scanner.Err()
+}
+
+// === key reading
+// ===
+
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyEnter
+	keySpace
+	keySlash
+)
+
+// readKey reads a single key press from the shared stdin reader,
+// translating the arrow-key escape sequences emitted by most terminals
+// into keyUp/keyDown. It reuses the same *bufio.Reader as every other
+// blocking read in the package so read-ahead bytes are never dropped
+// between calls.
+func readKey() (key, error) {
+	b, err := stdin.ReadByte()
+	if err != nil {
+		return keyNone, err
+	}
+
+	switch b {
+	case '\r', '\n':
+		return keyEnter, nil
+	case ' ':
+		return keySpace, nil
+	case '/':
+		return keySlash, nil
+	case '\033':
+		b2, _ := stdin.ReadByte()
+		b3, _ := stdin.ReadByte()
+		if b2 == '[' {
+			switch b3 {
+			case 'A':
+				return keyUp, nil
+			case 'B':
+				return keyDown, nil
+			}
+		}
+	}
+	return keyNone, nil
+}
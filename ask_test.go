@@ -0,0 +1,33 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterChoices(t *testing.T) {
+	choices := []string{"red", "green", "blue", "grey"}
+
+	if got := filterChoices(choices, ""); !reflect.DeepEqual(got, choices) {
+		t.Errorf("filterChoices(choices, \"\") = %v, want %v", got, choices)
+	}
+
+	got := filterChoices(choices, "gr")
+	want := []string{"green", "grey"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterChoices(choices, \"gr\") = %v, want %v", got, want)
+	}
+
+	if got := filterChoices(choices, "purple"); got != nil {
+		t.Errorf("filterChoices(choices, \"purple\") = %v, want nil", got)
+	}
+}
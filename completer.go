@@ -0,0 +1,176 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Completer computes the completion candidates for the buffer contents
+// "line" with the cursor at byte offset "pos". prefixLen is the number of
+// bytes immediately before pos that the candidates replace.
+type Completer interface {
+	Complete(line string, pos int) (candidates []string, prefixLen int)
+}
+
+// === WordListCompleter
+// ===
+
+// WordListCompleter completes the word immediately before the cursor
+// against a fixed list of words.
+type WordListCompleter struct {
+	Words []string
+}
+
+// NewWordListCompleter returns a WordListCompleter over words.
+func NewWordListCompleter(words []string) *WordListCompleter {
+	return &WordListCompleter{Words: words}
+}
+
+func (c *WordListCompleter) Complete(line string, pos int) (candidates []string, prefixLen int) {
+	word, start := wordBefore(line, pos)
+
+	for _, w := range c.Words {
+		if strings.HasPrefix(w, word) {
+			candidates = append(candidates, w)
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates, pos - start
+}
+
+// === FileCompleter
+// ===
+
+// FileCompleter completes the path immediately before the cursor against
+// the filesystem, expanding a leading "~" to the user's home directory.
+type FileCompleter struct{}
+
+// NewFileCompleter returns a FileCompleter.
+func NewFileCompleter() *FileCompleter {
+	return &FileCompleter{}
+}
+
+func (c *FileCompleter) Complete(line string, pos int) (candidates []string, prefixLen int) {
+	word, start := wordBefore(line, pos)
+
+	expanded := word
+	if strings.HasPrefix(expanded, "~") {
+		if home := os.Getenv("HOME"); home != "" {
+			expanded = home + expanded[1:]
+		}
+	}
+
+	dir, base := filepath.Split(expanded)
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, pos - start
+	}
+
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), base) {
+			continue
+		}
+
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		candidates = append(candidates, filepath.Join(filepath.Dir(word+"x"), name))
+	}
+
+	sort.Strings(candidates)
+	return candidates, pos - start
+}
+
+// === ChainCompleter
+// ===
+
+// ChainCompleter tries each of its Completers in turn and returns the
+// candidates of the first one that produces any.
+type ChainCompleter struct {
+	Completers []Completer
+}
+
+// NewChainCompleter combines several completers into one.
+func NewChainCompleter(completers ...Completer) *ChainCompleter {
+	return &ChainCompleter{Completers: completers}
+}
+
+func (c *ChainCompleter) Complete(line string, pos int) (candidates []string, prefixLen int) {
+	for _, comp := range c.Completers {
+		if candidates, prefixLen = comp.Complete(line, pos); len(candidates) > 0 {
+			return candidates, prefixLen
+		}
+	}
+	return nil, 0
+}
+
+// === Line wiring
+// ===
+
+// NewLineWithCompleter is like NewLine but also installs completer, so Tab
+// triggers completion from the first keystroke. hist takes the same
+// History interface as NewLine, not the concrete *history type, since the
+// two live in different packages.
+func NewLineWithCompleter(hist History, completer Completer) *Line {
+	ln := NewLine(hist)
+	ln.SetCompleter(completer)
+	return ln
+}
+
+// SetCompleter installs completer as the source of Tab-completion
+// candidates for ln, replacing any completer it already had.
+func (ln *Line) SetCompleter(completer Completer) {
+	ln.completer = completer
+}
+
+// === helpers
+// ===
+
+// wordBefore returns the word ending at pos and the byte offset it starts
+// at, where a word is delimited by whitespace.
+func wordBefore(line string, pos int) (word string, start int) {
+	start = pos
+	for start > 0 && !isWordBoundary(line[start-1]) {
+		start--
+	}
+	return line[start:pos], start
+}
+
+func isWordBoundary(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+// commonPrefix returns the longest common prefix of candidates.
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+			if prefix == "" {
+				return ""
+			}
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,55 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import "testing"
+
+func TestWordListCompleterComplete(t *testing.T) {
+	c := NewWordListCompleter([]string{"status", "stash", "start", "log"})
+
+	candidates, prefixLen := c.Complete("git st", 6)
+	if prefixLen != 2 {
+		t.Errorf("prefixLen = %d, want 2", prefixLen)
+	}
+	want := []string{"start", "stash", "status"}
+	if len(candidates) != len(want) {
+		t.Fatalf("candidates = %v, want %v", candidates, want)
+	}
+	for i, w := range want {
+		if candidates[i] != w {
+			t.Errorf("candidates[%d] = %q, want %q", i, candidates[i], w)
+		}
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	cases := []struct {
+		in   []string
+		want string
+	}{
+		{[]string{"status", "start", "stash"}, "sta"},
+		{[]string{"log"}, "log"},
+		{[]string{"log", "commit"}, ""},
+		{nil, ""},
+	}
+
+	for _, c := range cases {
+		if got := commonPrefix(c.in); got != c.want {
+			t.Errorf("commonPrefix(%v) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestWordBefore(t *testing.T) {
+	word, start := wordBefore("git checkout ma", 15)
+	if word != "ma" || start != 13 {
+		t.Errorf("wordBefore = %q, %d, want \"ma\", 13", word, start)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// EventType identifies the kind of Event delivered on Line.Events().
+type EventType int
+
+const (
+	EventKey EventType = iota
+	EventResize
+	EventPaste
+)
+
+// Event is a single terminal notification: a keystroke, a SIGWINCH resize,
+// or a coalesced bracketed paste.
+type Event struct {
+	Type EventType
+	Key  byte   // valid when Type == EventKey
+	Cols int    // valid when Type == EventResize
+	Rows int    // valid when Type == EventResize
+	Text string // valid when Type == EventPaste
+}
+
+// bracketedPasteStart and bracketedPasteEnd are the escape sequences a
+// terminal wraps a paste in when bracketed paste mode is enabled.
+const (
+	bracketedPasteStart = "\033[200~"
+	bracketedPasteEnd   = "\033[201~"
+)
+
+// events streams keystroke-level Events from stdin. It is created once per
+// Line and shared between ReadContext and Events; Read reads stdin directly.
+type events struct {
+	ch     chan Event
+	errCh  chan error
+	raw    chan byte
+	resize chan os.Signal
+}
+
+// newEvents starts the reader goroutine and the SIGWINCH watcher feeding ch.
+func newEvents() *events {
+	e := &events{
+		ch:     make(chan Event),
+		errCh:  make(chan error, 1),
+		raw:    make(chan byte, 256),
+		resize: make(chan os.Signal, 1),
+	}
+
+	signal.Notify(e.resize, syscall.SIGWINCH)
+
+	go e.readLoop()
+	go e.dispatchLoop()
+
+	return e
+}
+
+// readLoop feeds raw bytes from stdin into e.raw until stdin is closed or
+// errors out. It reads through the package-level shared stdin reader, like
+// every other blocking read, so bytes already buffered there before
+// Events/ReadContext is first called aren't invisible to a second,
+// independent reader on os.Stdin.
+func (e *events) readLoop() {
+	for {
+		b, err := stdin.ReadByte()
+		if err == nil {
+			e.raw <- b
+			continue
+		}
+		e.errCh <- err
+		return
+	}
+}
+
+// dispatchLoop turns raw bytes into Events, coalescing bracketed-paste runs
+// into a single EventPaste instead of interpreting the pasted bytes as
+// individual keybindings. Bytes that are still a candidate prefix of the
+// paste-start marker are held in pending rather than emitted immediately,
+// so the first few bytes of a paste-start sequence (or of any arrow-key
+// escape sequence, which shares the same leading bytes) never leak out as
+// individual key events before paste mode has a chance to engage.
+func (e *events) dispatchLoop() {
+	var pending []byte
+	inPaste := false
+	var pasted []byte
+
+	flushPending := func() {
+		for _, pb := range pending {
+			e.ch <- Event{Type: EventKey, Key: pb}
+		}
+		pending = nil
+	}
+
+	for {
+		select {
+		case b := <-e.raw:
+			if inPaste {
+				pasted = append(pasted, b)
+				if len(pasted) >= len(bracketedPasteEnd) &&
+					string(pasted[len(pasted)-len(bracketedPasteEnd):]) == bracketedPasteEnd {
+					text := pasted[:len(pasted)-len(bracketedPasteEnd)]
+					e.ch <- Event{Type: EventPaste, Text: string(text)}
+					inPaste = false
+					pasted = nil
+				}
+				continue
+			}
+
+			pending = append(pending, b)
+
+			if string(pending) == bracketedPasteStart {
+				inPaste = true
+				pending = nil
+				pasted = nil
+				continue
+			}
+
+			if !isPrefixOf(pending, bracketedPasteStart) {
+				flushPending()
+			}
+			// else: pending could still grow into the paste marker, so
+			// hold these bytes back until that's resolved one way or
+			// the other.
+
+		case <-e.resize:
+			cols, rows := termSize()
+			e.ch <- Event{Type: EventResize, Cols: cols, Rows: rows}
+		}
+	}
+}
+
+// isPrefixOf reports whether b is a prefix of s.
+func isPrefixOf(b []byte, s string) bool {
+	if len(b) > len(s) {
+		return false
+	}
+	return string(b) == s[:len(b)]
+}
+
+// termSize reads the current terminal size, falling back to 0,0 when it
+// cannot be determined (e.g. stdout is not a TTY).
+func termSize() (cols, rows int) {
+	type winsize struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+	ws := &winsize{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
+		os.Stdout.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(ws)))
+	if errno != 0 {
+		return 0, 0
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// Events returns the channel of keystroke-level events for this Line. It
+// does not wait for Enter: callers see each key as it arrives.
+func (ln *Line) Events() <-chan Event {
+	if ln.events == nil {
+		ln.events = newEvents()
+	}
+	return ln.events.ch
+}
+
+// ReadContext reads a line from the Line's event stream, starting from an
+// empty buffer, and returns ctx.Err() if ctx is cancelled before the user
+// finishes the line, calling RestoreTerm first. Unlike Read it does not put
+// the terminal into raw mode or print the prompt, and Ctrl-C, Ctrl-D and
+// Tab are looked up in the KeyMap like any other control key.
+func (ln *Line) ReadContext(ctx context.Context) (string, error) {
+	if ln.events == nil {
+		ln.events = newEvents()
+	}
+	ln.buf = ln.buf[:0]
+	ln.pos = 0
+	ln.escBuf = nil
+
+	resultCh := make(chan readResult, 1)
+	go func() {
+		line, err := ln.readFromEvents(ln.events.ch)
+		resultCh <- readResult{line, err}
+	}()
+
+	select {
+	case res := <-resultCh:
+		return res.line, res.err
+	case <-ctx.Done():
+		ln.RestoreTerm()
+		return "", ctx.Err()
+	}
+}
+
+type readResult struct {
+	line string
+	err  error
+}
+
+// readFromEvents drains ev, feeding each key into the line's buffer via the
+// existing key-handling machinery, until Enter (or an error) is seen.
+func (ln *Line) readFromEvents(ev <-chan Event) (string, error) {
+	for evt := range ev {
+		switch evt.Type {
+		case EventKey:
+			if evt.Key == '\r' || evt.Key == '\n' {
+				return ln.finish(), nil
+			}
+			ln.feedKey(evt.Key)
+		case EventPaste:
+			ln.feedPaste(evt.Text)
+		case EventResize:
+			ln.handleResize(evt.Cols, evt.Rows)
+		}
+	}
+	return "", io.EOF
+}
+
+// feedKey applies a single keystroke event to the line's buffer. It keeps
+// just enough state (escBuf) to recognize the arrow-key escape sequences
+// and Alt-combinations that arrive as several separate EventKeys.
+func (ln *Line) feedKey(b byte) {
+	defer ln.render()
+
+	if ln.escBuf != nil {
+		ln.escBuf = append(ln.escBuf, b)
+
+		if len(ln.escBuf) == 1 && ln.escBuf[0] != '[' {
+			seq := "alt-" + string(rune(ln.escBuf[0]))
+			ln.escBuf = nil
+			if action, ok := ln.keyMap.Lookup(seq); ok {
+				action(ln)
+			}
+			return
+		}
+
+		if len(ln.escBuf) == 2 {
+			seq := ""
+			switch ln.escBuf[1] {
+			case 'A':
+				seq = "up"
+			case 'B':
+				seq = "down"
+			case 'C':
+				seq = "right"
+			case 'D':
+				seq = "left"
+			}
+			ln.escBuf = nil
+			if seq != "" {
+				if action, ok := ln.keyMap.Lookup(seq); ok {
+					action(ln)
+				}
+			}
+		}
+		return
+	}
+
+	switch {
+	case b == 127 || b == 8:
+		ln.backspace()
+	case b == 27:
+		ln.escBuf = []byte{}
+	case b >= 1 && b <= 26:
+		if action, ok := ln.keyMap.Lookup("ctrl-" + string(rune('a'+b-1))); ok {
+			action(ln)
+		}
+	default:
+		ln.insert(string(b))
+	}
+}
+
+// feedPaste inserts an entire coalesced paste at the cursor in one go,
+// instead of interpreting its bytes as individual keystrokes.
+func (ln *Line) feedPaste(text string) {
+	ln.insert(text)
+	ln.render()
+}
+
+// handleResize redraws the line after a terminal resize. cols and rows are
+// not used: render's output does not depend on the terminal size.
+func (ln *Line) handleResize(cols, rows int) {
+	ln.render()
+}
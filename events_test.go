@@ -0,0 +1,66 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsPrefixOf(t *testing.T) {
+	if !isPrefixOf([]byte("\033["), bracketedPasteStart) {
+		t.Error("\\033[ should be a prefix of the paste-start marker")
+	}
+	if isPrefixOf([]byte("\033[A"), bracketedPasteStart) {
+		t.Error("\\033[A should not be a prefix of the paste-start marker")
+	}
+}
+
+// newTestEvents builds an events value wired for dispatchLoop without
+// starting readLoop or the SIGWINCH watcher, so tests can feed bytes
+// directly through e.raw.
+func newTestEvents() *events {
+	return &events{
+		ch:     make(chan Event),
+		raw:    make(chan byte, 64),
+		resize: make(chan os.Signal, 1),
+	}
+}
+
+// TestDispatchLoopDoesNotLeakPasteMarkerBytes guards against the bug where
+// every byte of the paste-start marker was emitted as its own EventKey as
+// soon as it arrived, with only the byte that completed the match
+// suppressed -- so a real paste leaked the first 5 bytes of "\033[200~" as
+// keystrokes before paste mode ever engaged.
+func TestDispatchLoopDoesNotLeakPasteMarkerBytes(t *testing.T) {
+	e := newTestEvents()
+	go e.dispatchLoop()
+
+	for _, b := range []byte(bracketedPasteStart + "hi" + bracketedPasteEnd) {
+		e.raw <- b
+	}
+
+	evt := <-e.ch
+	if evt.Type != EventPaste || evt.Text != "hi" {
+		t.Fatalf("first event = %+v, want EventPaste \"hi\" with no EventKey ahead of it", evt)
+	}
+}
+
+func TestDispatchLoopDeliversPlainKeysUnchanged(t *testing.T) {
+	e := newTestEvents()
+	go e.dispatchLoop()
+
+	e.raw <- 'a'
+
+	evt := <-e.ch
+	if evt.Type != EventKey || evt.Key != 'a' {
+		t.Fatalf("got %+v, want EventKey 'a'", evt)
+	}
+}
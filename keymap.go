@@ -0,0 +1,183 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+// KeySeq is a textual key sequence such as "ctrl-a" or "alt-b", as accepted
+// by KeyMap.Bind.
+type KeySeq string
+
+// Action is an editing operation bound to a KeySeq. It receives the Line
+// being edited so it can inspect or mutate the buffer and cursor.
+type Action func(ln *Line)
+
+// viMode tracks whether a ViKeyMap is currently in insert or normal state.
+type viMode int
+
+const (
+	viInsert viMode = iota
+	viNormal
+)
+
+// KeyMap maps key sequences to editing Actions. Line.Read looks up Enter,
+// Escape, the arrow keys and the Ctrl- and Alt- combinations in the Line's
+// current KeyMap, so programs can rebind or extend them. Ctrl-C, Ctrl-D on
+// an empty line, Tab and Backspace are handled by Read itself and are not
+// looked up, and printable bytes are inserted directly (or, in vi normal
+// mode, read as vi commands).
+type KeyMap struct {
+	bindings map[KeySeq]Action
+	mode     viMode // only meaningful for modal (vi) maps
+	modal    bool
+}
+
+// NewKeyMap returns an empty, non-modal KeyMap.
+func NewKeyMap() *KeyMap {
+	return &KeyMap{bindings: make(map[KeySeq]Action)}
+}
+
+// Bind associates seq with action, overriding any previous binding.
+func (km *KeyMap) Bind(seq string, action Action) {
+	km.bindings[KeySeq(seq)] = action
+}
+
+// Lookup returns the Action bound to seq, if any.
+func (km *KeyMap) Lookup(seq string) (Action, bool) {
+	action, ok := km.bindings[KeySeq(seq)]
+	return action, ok
+}
+
+// === Emacs
+// ===
+
+// EmacsKeyMap returns the default key map, matching the library's original
+// behaviour plus the usual Emacs-style shortcuts.
+func EmacsKeyMap() *KeyMap {
+	km := NewKeyMap()
+
+	km.Bind("ctrl-a", actionMoveStart)
+	km.Bind("ctrl-e", actionMoveEnd)
+	km.Bind("ctrl-k", actionKillToEnd)
+	km.Bind("ctrl-u", actionKillToStart)
+	km.Bind("ctrl-w", actionKillWord)
+	km.Bind("ctrl-y", actionYank)
+	km.Bind("alt-b", actionMoveWordLeft)
+	km.Bind("alt-f", actionMoveWordRight)
+	km.Bind("alt-d", actionKillWordForward)
+	km.Bind("left", actionMoveLeft)
+	km.Bind("right", actionMoveRight)
+	km.Bind("up", actionHistoryPrev)
+	km.Bind("down", actionHistoryNext)
+	km.Bind("enter", actionAcceptLine)
+
+	return km
+}
+
+// === Vi
+// ===
+
+// ViKeyMap returns a modal key map: it starts in insert mode like a normal
+// line editor, switches to normal mode on Escape, and interprets the
+// classic vi motions ("h j k l w b", "dd", "yy", "p", ...) while there.
+func ViKeyMap() *KeyMap {
+	km := NewKeyMap()
+	km.modal = true
+	km.mode = viInsert
+
+	km.Bind("esc", actionViNormalMode)
+	km.Bind("i", actionViInsertMode)
+	km.Bind("enter", actionAcceptLine)
+
+	km.Bind("vi-h", actionMoveLeft)
+	km.Bind("vi-l", actionMoveRight)
+	km.Bind("vi-w", actionMoveWordRight)
+	km.Bind("vi-b", actionMoveWordLeft)
+	km.Bind("vi-k", actionHistoryPrev)
+	km.Bind("vi-j", actionHistoryNext)
+	km.Bind("vi-dd", actionKillLine)
+	km.Bind("vi-yy", actionYankLine)
+	km.Bind("vi-p", actionYank)
+
+	return km
+}
+
+// Mode reports whether the map is currently in vi's insert or normal state.
+// It always returns viInsert for non-modal maps such as EmacsKeyMap.
+func (km *KeyMap) Mode() viMode {
+	return km.mode
+}
+
+func actionViNormalMode(ln *Line) { ln.keyMap.mode = viNormal }
+func actionViInsertMode(ln *Line) { ln.keyMap.mode = viInsert }
+
+// === Line wiring
+// ===
+
+// SetKeyMap installs km as the active key map for ln.
+func (ln *Line) SetKeyMap(km *KeyMap) {
+	ln.keyMap = km
+}
+
+// === built-in actions
+// ===
+// Each action mirrors one of the editing operations Line.Read used to
+// implement inline; they are now reachable through the KeyMap so a custom
+// map can rebind or drop any of them.
+
+func actionMoveStart(ln *Line)       { ln.moveCursor(-ln.cursorPos()) }
+func actionMoveEnd(ln *Line)         { ln.moveCursor(len(ln.buffer()) - ln.cursorPos()) }
+func actionMoveLeft(ln *Line)        { ln.moveCursor(-1) }
+func actionMoveRight(ln *Line)       { ln.moveCursor(1) }
+func actionMoveWordLeft(ln *Line)    { ln.moveCursor(-wordDistance(ln.buffer(), ln.cursorPos(), -1)) }
+func actionMoveWordRight(ln *Line)   { ln.moveCursor(wordDistance(ln.buffer(), ln.cursorPos(), 1)) }
+func actionKillToEnd(ln *Line)       { ln.kill(ln.cursorPos(), len(ln.buffer())) }
+func actionKillToStart(ln *Line)     { ln.kill(0, ln.cursorPos()) }
+func actionKillLine(ln *Line)        { ln.kill(0, len(ln.buffer())) }
+func actionKillWord(ln *Line) {
+	start := ln.cursorPos() - wordDistance(ln.buffer(), ln.cursorPos(), -1)
+	ln.kill(start, ln.cursorPos())
+}
+func actionKillWordForward(ln *Line) {
+	end := ln.cursorPos() + wordDistance(ln.buffer(), ln.cursorPos(), 1)
+	ln.kill(ln.cursorPos(), end)
+}
+func actionYank(ln *Line)       { ln.insert(ln.killRing()) }
+func actionYankLine(ln *Line)   { ln.setKillRing(ln.buffer()) }
+func actionHistoryPrev(ln *Line) { ln.historyPrev() }
+func actionHistoryNext(ln *Line) { ln.historyNext() }
+func actionAcceptLine(ln *Line) { ln.accept() }
+
+// wordDistance returns the number of bytes between pos and the start (dir
+// == -1) or end (dir == 1) of the adjacent word in s.
+func wordDistance(s string, pos, dir int) int {
+	i := pos
+	n := 0
+
+	if dir < 0 {
+		for i > 0 && s[i-1] == ' ' {
+			i--
+			n++
+		}
+		for i > 0 && s[i-1] != ' ' {
+			i--
+			n++
+		}
+	} else {
+		for i < len(s) && s[i] == ' ' {
+			i++
+			n++
+		}
+		for i < len(s) && s[i] != ' ' {
+			i++
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,89 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import "testing"
+
+func TestWordDistance(t *testing.T) {
+	s := "hello world"
+
+	if n := wordDistance(s, len(s), -1); n != 5 {
+		t.Errorf("wordDistance(end, -1) = %d, want 5", n)
+	}
+	if n := wordDistance(s, 0, 1); n != 5 {
+		t.Errorf("wordDistance(start, 1) = %d, want 5", n)
+	}
+	if n := wordDistance(s, 5, 1); n != 6 {
+		t.Errorf("wordDistance(5, 1) = %d, want 6 (skip the space too)", n)
+	}
+}
+
+func TestKeyMapBindLookup(t *testing.T) {
+	km := NewKeyMap()
+	called := false
+	km.Bind("ctrl-t", func(ln *Line) { called = true })
+
+	action, ok := km.Lookup("ctrl-t")
+	if !ok {
+		t.Fatal("Lookup did not find the binding just made with Bind")
+	}
+	action(nil)
+	if !called {
+		t.Error("looked-up action was not the one bound")
+	}
+
+	if _, ok := km.Lookup("ctrl-z"); ok {
+		t.Error("Lookup found a binding that was never made")
+	}
+}
+
+func TestEmacsKeyMapMovesCursor(t *testing.T) {
+	ln := &Line{keyMap: EmacsKeyMap()}
+	ln.setBuffer("hello")
+	ln.moveCursor(-100) // start at 0
+
+	action, ok := ln.keyMap.Lookup("ctrl-e")
+	if !ok {
+		t.Fatal("EmacsKeyMap has no ctrl-e binding")
+	}
+	action(ln)
+	if ln.cursorPos() != len("hello") {
+		t.Errorf("cursorPos after ctrl-e = %d, want %d", ln.cursorPos(), len("hello"))
+	}
+
+	action, ok = ln.keyMap.Lookup("ctrl-a")
+	if !ok {
+		t.Fatal("EmacsKeyMap has no ctrl-a binding")
+	}
+	action(ln)
+	if ln.cursorPos() != 0 {
+		t.Errorf("cursorPos after ctrl-a = %d, want 0", ln.cursorPos())
+	}
+}
+
+func TestViKeyMapIsModal(t *testing.T) {
+	km := ViKeyMap()
+	if !km.modal {
+		t.Error("ViKeyMap should be modal")
+	}
+	if km.Mode() != viInsert {
+		t.Errorf("ViKeyMap starts in mode %v, want viInsert", km.Mode())
+	}
+
+	ln := &Line{keyMap: km}
+	action, ok := km.Lookup("esc")
+	if !ok {
+		t.Fatal("ViKeyMap has no esc binding")
+	}
+	action(ln)
+	if km.Mode() != viNormal {
+		t.Error("esc did not switch ViKeyMap to viNormal")
+	}
+}
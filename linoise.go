@@ -0,0 +1,416 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// output is where Line and Question write prompts, answers and redraws.
+var output io.Writer = os.Stdout
+
+// stdin is the single bufio.Reader shared by every blocking read of
+// os.Stdin, so that read-ahead bytes buffered by one call are still
+// available to the next instead of being silently dropped.
+var stdin = bufio.NewReader(os.Stdin)
+
+// Errors returned by Line.Read.
+var (
+	ErrCtrlD = errors.New("linoise: end of input (Ctrl-D)")
+	ErrCtrlC = errors.New("linoise: interrupted (Ctrl-C)")
+)
+
+// Echo controls how Line renders the buffer to the terminal as the user
+// types.
+type Echo int
+
+const (
+	EchoNormal Echo = iota // render the buffer as typed
+	EchoNone               // render nothing, used by AskPassword
+	EchoMask               // render '*' for every byte typed
+)
+
+// History is the subset of behaviour Line needs from a history backend:
+// enough to record an accepted line. The *history type returned by
+// NewHistory satisfies it.
+type History interface {
+	Add(line string)
+}
+
+// === Type
+// ===
+
+// Line reads a single line of input, optionally backed by a History, a
+// Completer and a KeyMap.
+type Line struct {
+	prompt  string
+	ansiLen int
+	hist    History
+	echo    Echo
+
+	completer Completer
+	keyMap    *KeyMap
+	events    *events
+
+	buf       []byte
+	pos       int
+	killed    string
+	recent    []string
+	recentIdx int
+	done      bool
+	escBuf    []byte // partial escape sequence, used by feedKey
+}
+
+// NewLine returns a Line with no prompt, recording accepted lines to hist
+// (which may be nil to keep no history).
+func NewLine(hist History) *Line {
+	return NewLinePrompt("", 0, hist)
+}
+
+// NewLinePrompt returns a Line that renders prompt before the input.
+// ansiLen is the number of bytes of prompt that are ANSI escape sequences
+// rather than visible characters. It is stored on the Line; render does not
+// use it, as it moves the cursor relative to the end of the buffer.
+func NewLinePrompt(prompt string, ansiLen int, hist History) *Line {
+	return &Line{
+		prompt:  prompt,
+		ansiLen: ansiLen,
+		hist:    hist,
+		keyMap:  EmacsKeyMap(),
+	}
+}
+
+// RestoreTerm restores the terminal settings that tty.RawMode changed.
+func (ln *Line) RestoreTerm() {
+	tty.Restore()
+}
+
+// SetEcho changes how the buffer is rendered as the user types.
+func (ln *Line) SetEcho(echo Echo) {
+	ln.echo = echo
+}
+
+// === Reading
+// ===
+
+// Read prints the prompt and blocks until the user accepts a line (Enter)
+// or an error occurs (Ctrl-C, Ctrl-D on an empty line, or an I/O error).
+// When the terminal can not be put into raw mode -- e.g. stdin is a pipe --
+// Read falls back to a plain buffered line read so scripts still work.
+func (ln *Line) Read() (string, error) {
+	if err := tty.RawMode(); err != nil {
+		return ln.readFallback()
+	}
+	defer tty.Restore()
+
+	ln.buf = ln.buf[:0]
+	ln.pos = 0
+	ln.done = false
+	ln.render()
+
+	for {
+		b, err := stdin.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case b == 4 && len(ln.buf) == 0: // Ctrl-D on an empty line
+			return "", ErrCtrlD
+		case b == 3: // Ctrl-C
+			return "", ErrCtrlC
+		case b == 9: // Tab
+			ln.complete()
+			ln.render()
+			continue
+		case b == 127 || b == 8: // Backspace
+			ln.backspace()
+			ln.render()
+			continue
+		}
+
+		seq := ""
+		switch {
+		case b == 13 || b == 10:
+			seq = "enter"
+		case b == 27:
+			if stdin.Buffered() == 0 {
+				seq = "esc"
+			} else {
+				seq, _ = ln.readEscape()
+			}
+		case b >= 1 && b <= 26:
+			seq = "ctrl-" + string(rune('a'+b-1))
+		}
+
+		if seq != "" {
+			if action, ok := ln.keyMap.Lookup(seq); ok {
+				action(ln)
+				if ln.done {
+					return ln.finish(), nil
+				}
+				ln.render()
+			}
+			continue
+		}
+
+		if ln.keyMap.modal && ln.keyMap.mode == viNormal {
+			if ln.dispatchViNormal(b) {
+				ln.render()
+			}
+			continue
+		}
+
+		ln.insert(string(b))
+		ln.render()
+	}
+}
+
+// finish records the accepted line in history and returns it; it is the
+// common tail of Read's raw-mode loop and of ReadContext. readFallback
+// records the line itself.
+func (ln *Line) finish() string {
+	line := ln.buffer()
+	io.WriteString(output, "\r\n")
+	if ln.hist != nil {
+		ln.hist.Add(line)
+	}
+	ln.recent = append(ln.recent, line)
+	ln.recentIdx = 0
+	return line
+}
+
+// readFallback reads a single line with bufio, for use when raw mode can't
+// be entered (stdin is not a TTY).
+func (ln *Line) readFallback() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		if err == io.EOF {
+			return "", ErrCtrlD
+		}
+		return "", err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	if ln.hist != nil {
+		ln.hist.Add(line)
+	}
+	ln.recent = append(ln.recent, line)
+	ln.recentIdx = 0
+	return line, nil
+}
+
+// readEscape consumes the bytes following an ESC that is not a bare
+// Escape keypress, translating arrow keys to their action names and any
+// other "ESC X" pair to an "alt-X" sequence. Any other "ESC [" sequence,
+// and a read error, yields "esc".
+func (ln *Line) readEscape() (string, error) {
+	b2, err := stdin.ReadByte()
+	if err != nil {
+		return "esc", err
+	}
+	if b2 != '[' {
+		return "alt-" + string(rune(b2)), nil
+	}
+
+	b3, err := stdin.ReadByte()
+	if err != nil {
+		return "esc", err
+	}
+	switch b3 {
+	case 'A':
+		return "up", nil
+	case 'B':
+		return "down", nil
+	case 'C':
+		return "right", nil
+	case 'D':
+		return "left", nil
+	}
+	return "esc", nil
+}
+
+// dispatchViNormal handles a byte read while a modal KeyMap is in normal
+// mode, including the two-key "dd"/"yy" commands. It returns false when b
+// doesn't start a recognized vi command.
+func (ln *Line) dispatchViNormal(b byte) bool {
+	switch b {
+	case 'd':
+		if b2, err := stdin.ReadByte(); err == nil && b2 == 'd' {
+			if action, ok := ln.keyMap.Lookup("vi-dd"); ok {
+				action(ln)
+			}
+		}
+		return true
+	case 'y':
+		if b2, err := stdin.ReadByte(); err == nil && b2 == 'y' {
+			if action, ok := ln.keyMap.Lookup("vi-yy"); ok {
+				action(ln)
+			}
+		}
+		return true
+	case 'h', 'l', 'w', 'b', 'k', 'j', 'p':
+		if action, ok := ln.keyMap.Lookup("vi-" + string(b)); ok {
+			action(ln)
+		}
+		return true
+	case 'i':
+		if action, ok := ln.keyMap.Lookup("i"); ok {
+			action(ln)
+		}
+		return true
+	}
+	return false
+}
+
+// render redraws the prompt and buffer in place.
+func (ln *Line) render() {
+	if ln.echo == EchoNone {
+		return
+	}
+
+	text := ln.buffer()
+	if ln.echo == EchoMask {
+		text = strings.Repeat("*", len(text))
+	}
+
+	io.WriteString(output, "\r"+ln.prompt+text+"\033[K")
+	if back := len(text) - ln.pos; back > 0 {
+		io.WriteString(output, escapeMoveLeft(back))
+	}
+}
+
+func escapeMoveLeft(n int) string {
+	return "\033[" + itoa(n) + "D"
+}
+
+func itoa(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	var digits []byte
+	for n > 0 {
+		digits = append([]byte{byte('0' + n%10)}, digits...)
+		n /= 10
+	}
+	return string(digits)
+}
+
+// === Buffer editing, used by KeyMap Actions
+// ===
+
+func (ln *Line) buffer() string { return string(ln.buf) }
+func (ln *Line) cursorPos() int { return ln.pos }
+
+func (ln *Line) moveCursor(delta int) {
+	ln.pos += delta
+	if ln.pos < 0 {
+		ln.pos = 0
+	}
+	if ln.pos > len(ln.buf) {
+		ln.pos = len(ln.buf)
+	}
+}
+
+func (ln *Line) insert(s string) {
+	ln.buf = append(ln.buf[:ln.pos], append([]byte(s), ln.buf[ln.pos:]...)...)
+	ln.pos += len(s)
+}
+
+func (ln *Line) backspace() {
+	if ln.pos > 0 {
+		ln.buf = append(ln.buf[:ln.pos-1], ln.buf[ln.pos:]...)
+		ln.pos--
+	}
+}
+
+func (ln *Line) kill(start, end int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(ln.buf) {
+		end = len(ln.buf)
+	}
+	if start >= end {
+		return
+	}
+	ln.killed = string(ln.buf[start:end])
+	ln.buf = append(ln.buf[:start], ln.buf[end:]...)
+	ln.pos = start
+}
+
+func (ln *Line) killRing() string         { return ln.killed }
+func (ln *Line) setKillRing(s string)     { ln.killed = s }
+
+func (ln *Line) setBuffer(s string) {
+	ln.buf = []byte(s)
+	ln.pos = len(ln.buf)
+}
+
+func (ln *Line) historyPrev() {
+	if ln.recentIdx < len(ln.recent) {
+		ln.recentIdx++
+		ln.setBuffer(ln.recent[len(ln.recent)-ln.recentIdx])
+	}
+}
+
+func (ln *Line) historyNext() {
+	switch {
+	case ln.recentIdx > 1:
+		ln.recentIdx--
+		ln.setBuffer(ln.recent[len(ln.recent)-ln.recentIdx])
+	case ln.recentIdx == 1:
+		ln.recentIdx = 0
+		ln.setBuffer("")
+	}
+}
+
+func (ln *Line) accept() { ln.done = true }
+
+// === Tab completion
+// ===
+
+func (ln *Line) complete() {
+	if ln.completer == nil {
+		return
+	}
+
+	candidates, prefixLen := ln.completer.Complete(ln.buffer(), ln.pos)
+	if len(candidates) == 0 {
+		return
+	}
+	if len(candidates) == 1 {
+		ln.replacePrefix(prefixLen, candidates[0])
+		return
+	}
+
+	if prefix := commonPrefix(candidates); len(prefix) > prefixLen {
+		ln.replacePrefix(prefixLen, prefix)
+		return
+	}
+
+	ln.showCandidates(candidates)
+}
+
+func (ln *Line) replacePrefix(prefixLen int, replacement string) {
+	start := ln.pos - prefixLen
+	ln.buf = append(ln.buf[:start], append([]byte(replacement), ln.buf[ln.pos:]...)...)
+	ln.pos = start + len(replacement)
+}
+
+// showCandidates prints the ambiguous candidates below the prompt; the
+// next render() redraws the prompt line itself afterwards.
+func (ln *Line) showCandidates(candidates []string) {
+	io.WriteString(output, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+}
@@ -11,138 +11,402 @@ package linoise
 
 import (
 	"bufio"
-	"container/ring"
+	"encoding/base64"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
-
 // Values by default
 var (
 	FilePerm   uint32 = 0600 // History file permission
-	HistoryCap = 500         // Capacity
+	HistoryCap        = 500  // Capacity
 )
 
-
-// === Type
+// === Types
 // ===
 
+// Entry is a single history record: its sequence number, the time it was
+// added and the command text itself. Seq is -1 for an entry added locally
+// that has not yet been assigned a number by Save.
+type Entry struct {
+	Seq  int64
+	Time time.Time
+	Line string
+}
+
+// HistSizeError is returned by NewHistorySize when size is not greater
+// than zero.
+type HistSizeError int
+
+func (e HistSizeError) Error() string {
+	return fmt.Sprintf("linoise: history size %d must be greater than zero", int(e))
+}
+
+// HistoryOptions configures a history beyond its capacity.
+type HistoryOptions struct {
+	Cap         int  // Maximum number of entries kept in memory
+	Dedup       bool // Skip consecutive duplicates on Add
+	IgnoreSpace bool // Skip lines starting with a space on Add
+	Shared      bool // Lock the file so other processes can share it safely
+}
+
 type history struct {
-	Cap, Len int
+	opts     HistoryOptions
 	filename string
 	file     *os.File
-	rng      *ring.Ring
+	entries  []Entry
+	offset   int64 // bytes of the file already read, used by Merge
+	synced   int64 // highest seq already assigned and written to the file
+	mu       sync.Mutex
 }
 
+// === Construction
+// ===
 
 // Base to create an history file.
-func _baseHistory(fname string, size int) (*history, os.Error) {
-	file, err := os.Open(fname, os.O_CREATE|os.O_RDWR, FilePerm)
+func _baseHistory(fname string, opts HistoryOptions) (*history, error) {
+	file, err := os.OpenFile(fname, os.O_CREATE|os.O_RDWR, os.FileMode(FilePerm))
 	if err != nil {
 		return nil, err
 	}
 
-	h := new(history)
-	h.filename = fname
-	h.file = file
-	h.Cap = size
-	h.rng = ring.New(size)
-
-	return h, nil
+	return &history{
+		opts:     opts,
+		filename: fname,
+		file:     file,
+		entries:  make([]Entry, 0, opts.Cap),
+		synced:   -1,
+	}, nil
 }
 
-// Creates a new history using the maximum length by default.
-func NewHistory(filename string) (*history, os.Error) {
-	return _baseHistory(filename, HistoryCap)
+// NewHistory creates a new history using the maximum length by default.
+func NewHistory(filename string) (*history, error) {
+	return _baseHistory(filename, HistoryOptions{Cap: HistoryCap})
 }
 
-// Creates a new history whose buffer has the specified size, which must be
-// greater than zero.
-func NewHistorySize(filename string, size int) (*history, os.Error) {
+// NewHistorySize creates a new history whose buffer has the specified size,
+// which must be greater than zero.
+func NewHistorySize(filename string, size int) (*history, error) {
 	if size <= 0 {
 		return nil, HistSizeError(size)
 	}
 
-	return _baseHistory(filename, size)
+	return _baseHistory(filename, HistoryOptions{Cap: size})
+}
+
+// NewHistoryOptions creates a new history governed by opts. A zero Cap falls
+// back to HistoryCap.
+func NewHistoryOptions(filename string, opts HistoryOptions) (*history, error) {
+	if opts.Cap <= 0 {
+		opts.Cap = HistoryCap
+	}
+	return _baseHistory(filename, opts)
 }
-// ===
 
+// ===
 
-// Adds a new line to the buffer.
+// Add appends a new line to the in-memory buffer, honouring the Dedup and
+// IgnoreSpace options.
 func (h *history) Add(line string) {
-	h.rng.Value = line
-	h.rng = h.rng.Next()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.opts.IgnoreSpace && strings.HasPrefix(line, " ") {
+		return
+	}
+	if h.opts.Dedup && len(h.entries) > 0 && h.entries[len(h.entries)-1].Line == line {
+		return
+	}
+
+	// Seq is assigned by Save, under the file lock, so that two processes
+	// sharing a history file never hand out the same number.
+	h.entries = append(h.entries, Entry{
+		Seq:  -1,
+		Time: time.Now(),
+		Line: line,
+	})
+
+	if len(h.entries) > h.opts.Cap {
+		h.entries = h.entries[len(h.entries)-h.opts.Cap:]
+	}
+}
+
+// Len returns the number of entries currently held in memory.
+func (h *history) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+// Search returns every entry whose line contains substr, oldest first.
+func (h *history) Search(substr string) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var out []Entry
+	for _, e := range h.entries {
+		if strings.Contains(e.Line, substr) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// SearchRegexp returns every entry whose line matches re, oldest first.
+func (h *history) SearchRegexp(re *regexp.Regexp) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var out []Entry
+	for _, e := range h.entries {
+		if re.MatchString(e.Line) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ReverseSearch returns the most recent entry whose line starts with
+// prefix, for Ctrl-R style incremental search.
+func (h *history) ReverseSearch(prefix string) (Entry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if strings.HasPrefix(h.entries[i].Line, prefix) {
+			return h.entries[i], true
+		}
+	}
+	return Entry{}, false
+}
+
+// === Persistence
+// ===
+
+// encodeEntry renders an entry as "seq\ttimestamp\tbase64(line)\n" so
+// newlines inside the command text can't corrupt the file format.
+func encodeEntry(e Entry) string {
+	return fmt.Sprintf("%d\t%d\t%s\n", e.Seq, e.Time.Unix(), base64.StdEncoding.EncodeToString([]byte(e.Line)))
+}
+
+// decodeEntry parses a line produced by encodeEntry.
+func decodeEntry(raw string) (Entry, error) {
+	fields := strings.SplitN(raw, "\t", 3)
+	if len(fields) != 3 {
+		return Entry{}, fmt.Errorf("history: malformed record %q", raw)
+	}
+
+	seq, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Entry{}, err
+	}
+	ts, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return Entry{}, err
+	}
+	line, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return Entry{}, err
+	}
 
-	if h.Len < h.Cap {
-		h.Len++
+	return Entry{Seq: seq, Time: time.Unix(ts, 0), Line: string(line)}, nil
+}
+
+// lock takes an exclusive flock on the history file when Shared is set.
+func (h *history) lock() error {
+	if !h.opts.Shared {
+		return nil
 	}
+	return syscall.Flock(int(h.file.Fd()), syscall.LOCK_EX)
 }
 
-// Loads the history from the file.
-func (h *history) Load() {
+// unlock releases the flock taken by lock.
+func (h *history) unlock() {
+	if !h.opts.Shared {
+		return
+	}
+	syscall.Flock(int(h.file.Fd()), syscall.LOCK_UN)
+}
+
+// Load reads every record from the history file into memory, replacing
+// whatever was there before.
+func (h *history) Load() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.lock(); err != nil {
+		return err
+	}
+	defer h.unlock()
+
+	if _, err := h.file.Seek(0, 0); err != nil {
+		return err
+	}
+
+	h.entries = h.entries[:0]
 	bufin := bufio.NewReader(h.file)
+	var read int64
+	var maxSeq int64
 
 	for {
 		line, err := bufin.ReadString('\n')
-		if err == os.EOF {
+		read += int64(len(line))
+		if line != "" {
+			e, derr := decodeEntry(strings.TrimRight(line, "\n"))
+			if derr == nil {
+				h.entries = append(h.entries, e)
+				if e.Seq > maxSeq {
+					maxSeq = e.Seq
+				}
+			}
+		}
+		if err != nil {
 			break
 		}
+	}
 
-		h.rng.Value = strings.TrimRight(line, "\n")
-		h.rng = h.rng.Next()
-		h.Len++
+	if len(h.entries) > h.opts.Cap {
+		h.entries = h.entries[len(h.entries)-h.opts.Cap:]
 	}
+
+	h.offset = read
+	h.synced = maxSeq
+	return nil
 }
 
-// Saves all lines to the text file, excep when:
-// + it starts with some space
-// + it is an empty line
-func (h *history) Save() (err os.Error) {
-	bufout := bufio.NewWriter(h.file)
+// Merge reconciles the in-memory history with records appended by other
+// processes since the last Load/Merge/Save, instead of blindly truncating
+// the file the way Save used to. Records already held in memory (matched
+// by Seq, which is only assigned once a record is actually persisted) are
+// skipped, and the merged result is kept sorted by Time so that entries
+// from different writers interleave in the order they were typed rather
+// than the order they happened to be read in. Times are compared to the
+// second, the resolution the file stores; within the same second, entries
+// already held in memory stay ahead of merged ones.
+func (h *history) Merge() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	if _, err = h.file.Seek(0, 0); err != nil {
-		return
+	if err := h.lock(); err != nil {
+		return err
 	}
+	defer h.unlock()
+	return h.mergeLocked()
+}
 
-	for v := range h.rng.Iter() {
-		if v != nil {
-			line := v.(string)
+// mergeLocked is Merge's implementation, callable by Save while already
+// holding h.mu and the file lock, so Save can merge and append under a
+// single hold of the lock.
+func (h *history) mergeLocked() error {
+	if _, err := h.file.Seek(h.offset, 0); err != nil {
+		return err
+	}
 
-			if strings.HasPrefix(line, " ") {
-				continue
-			}
-			if line = strings.TrimSpace(line); line == "" {
-				continue
-			}
-			if _, err = bufout.WriteString(line + "\n"); err != nil {
-				log.Println("history.Save:", err)
-				break
-			}
+	seen := make(map[int64]bool, len(h.entries))
+	for _, e := range h.entries {
+		if e.Seq >= 0 {
+			seen[e.Seq] = true
 		}
 	}
 
-	if err = bufout.Flush(); err != nil {
-		log.Println("history.Save:", err)
+	bufin := bufio.NewReader(h.file)
+	var read int64
+	for {
+		line, err := bufin.ReadString('\n')
+		read += int64(len(line))
+		if line != "" {
+			e, derr := decodeEntry(strings.TrimRight(line, "\n"))
+			if derr == nil && !seen[e.Seq] {
+				h.entries = append(h.entries, e)
+				seen[e.Seq] = true
+				if e.Seq > h.synced {
+					h.synced = e.Seq
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
 	}
 
-	h.closeFile()
-	return
-}
+	h.offset += read
+
+	sort.SliceStable(h.entries, func(i, j int) bool {
+		return h.entries[i].Time.Unix() < h.entries[j].Time.Unix()
+	})
 
-// Closes the file descriptor.
-func (h *history) closeFile() {
-	h.file.Close()
+	if len(h.entries) > h.opts.Cap {
+		h.entries = h.entries[len(h.entries)-h.opts.Cap:]
+	}
+	return nil
 }
 
-// Opens the file.
-/*func (h *history) openFile() {
-	file, err := os.Open(fname, os.O_CREATE|os.O_RDWR, FilePerm)
-	if err != nil {
-		log.Println("history.openFile:", err)
-		return
+// Save merges in any records written by other processes, assigns each
+// locally-added, not-yet-persisted entry the next available Seq under the
+// file lock, and appends them to the text file, excluding lines that:
+// + start with some space
+// + are empty
+func (h *history) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.lock(); err != nil {
+		return err
+	}
+	defer h.unlock()
+
+	if err := h.mergeLocked(); err != nil {
+		return err
 	}
 
-	h.file = file
-}*/
+	if _, err := h.file.Seek(0, 2); err != nil {
+		return err
+	}
 
+	nextSeq := h.synced + 1
+	bufout := bufio.NewWriter(h.file)
+	var wrote int64
+
+	for i := range h.entries {
+		e := &h.entries[i]
+		if e.Seq >= 0 {
+			continue // already persisted, either by us or by another writer
+		}
+
+		e.Seq = nextSeq
+		nextSeq++
+
+		line := strings.TrimSpace(e.Line)
+		if line == "" || strings.HasPrefix(e.Line, " ") {
+			continue
+		}
+		n, err := bufout.WriteString(encodeEntry(*e))
+		if err != nil {
+			log.Println("history.Save:", err)
+			break
+		}
+		wrote += int64(n)
+	}
+
+	if err := bufout.Flush(); err != nil {
+		log.Println("history.Save:", err)
+		return err
+	}
+
+	h.synced = nextSeq - 1
+	h.offset += wrote
+	return nil
+}
+
+// Close closes the underlying file descriptor.
+func (h *history) Close() error {
+	return h.file.Close()
+}
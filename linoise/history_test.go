@@ -0,0 +1,140 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestHistorySearch(t *testing.T) {
+	h, err := NewHistory(filepath.Join(t.TempDir(), "hist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	h.Add("cd /tmp")
+	h.Add("ls -la")
+	h.Add("cd /home")
+
+	if got := h.Search("cd"); len(got) != 2 {
+		t.Errorf("Search(\"cd\") = %d entries, want 2", len(got))
+	}
+
+	re := regexp.MustCompile(`^ls`)
+	if got := h.SearchRegexp(re); len(got) != 1 {
+		t.Errorf("SearchRegexp(^ls) = %d entries, want 1", len(got))
+	}
+
+	entry, ok := h.ReverseSearch("cd")
+	if !ok || entry.Line != "cd /home" {
+		t.Errorf("ReverseSearch(\"cd\") = %q, %v, want \"cd /home\", true", entry.Line, ok)
+	}
+}
+
+func TestHistoryDedup(t *testing.T) {
+	h, err := NewHistoryOptions(filepath.Join(t.TempDir(), "hist"), HistoryOptions{Cap: HistoryCap, Dedup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	h.Add("ls")
+	h.Add("ls")
+	h.Add("pwd")
+
+	if got := h.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+func TestHistorySaveLoadRoundtrip(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "hist")
+
+	h, err := NewHistory(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Add("first")
+	h.Add("second")
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+
+	h2, err := NewHistory(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h2.Close()
+	if err := h2.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := h2.Len(); got != 2 {
+		t.Fatalf("Len() after reload = %d, want 2", got)
+	}
+	if h2.entries[0].Line != "first" || h2.entries[1].Line != "second" {
+		t.Errorf("reloaded entries = %v", h2.entries)
+	}
+}
+
+// TestHistoryMergeDedupsAndOrders simulates two shells sharing a history
+// file: each saves a batch of its own commands, and the other's next Save
+// or Merge must merge those in without duplicating or losing either side's
+// Seq, keeping the commands in the order they were added.
+func TestHistoryMergeDedupsAndOrders(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "hist")
+	opts := HistoryOptions{Cap: HistoryCap, Shared: true}
+
+	h1, err := NewHistoryOptions(fname, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h1.Close()
+
+	h2, err := NewHistoryOptions(fname, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h2.Close()
+
+	h1.Add("shell-one cmd")
+	if err := h1.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	h2.Add("shell-two cmd")
+	if err := h2.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h1.Merge(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := h1.Len(); got != 2 {
+		t.Fatalf("h1.Len() after Merge = %d, want 2 (no duplicates)", got)
+	}
+
+	seen := make(map[int64]bool)
+	for _, e := range h1.entries {
+		if seen[e.Seq] {
+			t.Fatalf("duplicate Seq %d in merged entries: %v", e.Seq, h1.entries)
+		}
+		seen[e.Seq] = true
+	}
+
+	if h1.entries[0].Line != "shell-one cmd" || h1.entries[1].Line != "shell-two cmd" {
+		t.Errorf("merged entries out of order: %v", h1.entries)
+	}
+}
@@ -49,6 +49,8 @@ const (
 
 type Question struct {
 	trueString, falseString string // Strings that represent booleans.
+
+	selectLines int // lines printed by the previous AskSelect/AskMultiSelect frame
 }
 
 // Gets a question type.
@@ -66,8 +68,8 @@ func NewQuestion() *Question {
 	}
 
 	return &Question{
-		strings.ToLower(QuestionTrueString),
-		strings.ToLower(QuestionFalseString),
+		trueString:  strings.ToLower(QuestionTrueString),
+		falseString: strings.ToLower(QuestionFalseString),
 	}
 }
 
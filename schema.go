@@ -0,0 +1,288 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Validator checks whether a raw answer is acceptable, returning a
+// descriptive error when it is not.
+type Validator func(string) error
+
+var validators = map[string]Validator{
+	"email":    validateEmail,
+	"url":      validateURL,
+	"nonempty": validateNonEmpty,
+}
+
+// RegisterValidator makes v available to the "validate=name" struct tag
+// under the given name.
+func RegisterValidator(name string, v Validator) {
+	validators[name] = v
+}
+
+func validateEmail(s string) error {
+	_, err := mail.ParseAddress(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid email address", s)
+	}
+	return nil
+}
+
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not a valid URL", s)
+	}
+	return nil
+}
+
+func validateNonEmpty(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return fmt.Errorf("a value is required")
+	}
+	return nil
+}
+
+// lookupValidator resolves the "validate=..." tag value, including the
+// parametrized "regexp:PATTERN" and "range:min:max" forms.
+func lookupValidator(spec string) (Validator, error) {
+	if strings.HasPrefix(spec, "regexp:") {
+		pattern := strings.TrimPrefix(spec, "regexp:")
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(s string) error {
+			if !re.MatchString(s) {
+				return fmt.Errorf("%q does not match %q", s, pattern)
+			}
+			return nil
+		}, nil
+	}
+
+	if strings.HasPrefix(spec, "range:") {
+		parts := strings.Split(strings.TrimPrefix(spec, "range:"), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("range validator expects \"range:min:max\", got %q", spec)
+		}
+		min, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		max, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(s string) error {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a number", s)
+			}
+			if v < min || v > max {
+				return fmt.Errorf("%v is not between %v and %v", v, min, max)
+			}
+			return nil
+		}, nil
+	}
+
+	v, ok := validators[spec]
+	if !ok {
+		return nil, fmt.Errorf("linoise: unknown validator %q", spec)
+	}
+	return v, nil
+}
+
+// fieldSchema is the parsed form of a "linoise" struct tag.
+type fieldSchema struct {
+	prompt   string
+	def      string
+	required bool
+	secret   bool
+	choices  []string
+	validate string
+}
+
+// parseTag turns `linoise:"prompt=Your name,default=Anon,required"` into a
+// fieldSchema.
+func parseTag(tag, fieldName string) fieldSchema {
+	s := fieldSchema{prompt: fieldName}
+
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		key := kv[0]
+		val := ""
+		if len(kv) == 2 {
+			val = kv[1]
+		}
+
+		switch key {
+		case "prompt":
+			s.prompt = val
+		case "default":
+			s.def = val
+		case "required":
+			s.required = true
+		case "secret":
+			s.secret = true
+		case "choice":
+			s.choices = strings.Split(val, "|")
+		case "validate":
+			s.validate = val
+		}
+	}
+
+	return s
+}
+
+// UnsupportedKindError is returned by Ask when a struct field's kind has no
+// corresponding Read method.
+type UnsupportedKindError struct {
+	Field string
+	Kind  reflect.Kind
+}
+
+func (e UnsupportedKindError) Error() string {
+	return fmt.Sprintf("linoise: field %q has unsupported kind %s", e.Field, e.Kind)
+}
+
+// Ask walks dest, which must be a pointer to a struct, and prompts for each
+// exported field that has a "linoise" struct tag, using the tag to drive the
+// prompt text, default value, choices, and validation; fields without the
+// tag are left unchanged. Field kinds are dispatched to the
+// existing Read* methods; an unsupported kind returns UnsupportedKindError.
+func (q *Question) Ask(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("linoise: Ask expects a pointer to a struct, got %T", dest)
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		tag, ok := field.Tag.Lookup("linoise")
+		if !ok {
+			continue
+		}
+		schema := parseTag(tag, field.Name)
+
+		var validate Validator
+		if schema.validate != "" {
+			var err error
+			validate, err = lookupValidator(schema.validate)
+			if err != nil {
+				return err
+			}
+		}
+
+		fv := v.Field(i)
+
+		for {
+			raw, value, err := q.askField(schema, fv.Type())
+			if err != nil {
+				return err
+			}
+
+			if schema.required && raw == "" {
+				fmt.Fprintf(output, "%s%s is required\r\n", QuestionErrPrefix, schema.prompt)
+				continue
+			}
+
+			if validate != nil {
+				if verr := validate(raw); verr != nil {
+					fmt.Fprintf(output, "%s%v\r\n", QuestionErrPrefix, verr)
+					continue
+				}
+			}
+
+			fv.Set(value)
+			break
+		}
+	}
+
+	return nil
+}
+
+// askField prompts once for a single field, returning both the raw text
+// typed (for validation) and the reflect.Value ready to be set.
+func (q *Question) askField(schema fieldSchema, fieldType reflect.Type) (raw string, value reflect.Value, err error) {
+	if len(schema.choices) > 0 {
+		choice, err := q.ReadChoice(schema.prompt, schema.choices)
+		return choice, reflect.ValueOf(choice), err
+	}
+
+	if schema.secret {
+		pwd, err := q.AskPassword(schema.prompt)
+		return pwd, reflect.ValueOf(pwd), err
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		var s string
+		if schema.def != "" {
+			s, err = q.ReadStringDefault(schema.prompt, schema.def)
+		} else {
+			s, err = q.ReadString(schema.prompt)
+		}
+		return s, reflect.ValueOf(s), err
+
+	case reflect.Int:
+		var n int
+		if schema.def != "" {
+			def, _ := strconv.Atoi(schema.def)
+			n, err = q.ReadIntDefault(schema.prompt, def)
+		} else {
+			n, err = q.ReadInt(schema.prompt)
+		}
+		return strconv.Itoa(n), reflect.ValueOf(n), err
+
+	case reflect.Float64:
+		var f float64
+		if schema.def != "" {
+			def, _ := strconv.ParseFloat(schema.def, 64)
+			f, err = q.ReadFloatDefault(schema.prompt, def)
+		} else {
+			f, err = q.ReadFloat(schema.prompt)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), reflect.ValueOf(f), err
+
+	case reflect.Bool:
+		def := schema.def == "true"
+		b, err := q.ReadBool(schema.prompt, def)
+		return strconv.FormatBool(b), reflect.ValueOf(b), err
+
+	case reflect.Slice:
+		if fieldType.Elem().Kind() != reflect.String {
+			break
+		}
+		s, err := q.ReadString(schema.prompt)
+		words := strings.Split(s, ",")
+		return s, reflect.ValueOf(words), err
+	}
+
+	return "", reflect.Value{}, UnsupportedKindError{Field: schema.prompt, Kind: fieldType.Kind()}
+}
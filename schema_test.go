@@ -0,0 +1,77 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	s := parseTag("prompt=Your name,default=Anon,required", "Name")
+
+	if s.prompt != "Your name" || s.def != "Anon" || !s.required {
+		t.Errorf("parseTag = %+v", s)
+	}
+
+	s = parseTag("choice=red|green|blue", "Color")
+	want := []string{"red", "green", "blue"}
+	if !reflect.DeepEqual(s.choices, want) {
+		t.Errorf("parseTag choices = %v, want %v", s.choices, want)
+	}
+}
+
+func TestLookupValidatorBuiltins(t *testing.T) {
+	email, err := lookupValidator("email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := email("not-an-email"); err == nil {
+		t.Error("email validator accepted an invalid address")
+	}
+	if err := email("user@example.com"); err != nil {
+		t.Errorf("email validator rejected a valid address: %v", err)
+	}
+}
+
+func TestLookupValidatorRange(t *testing.T) {
+	v, err := lookupValidator("range:1:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v("5"); err != nil {
+		t.Errorf("range validator rejected an in-range value: %v", err)
+	}
+	if err := v("20"); err == nil {
+		t.Error("range validator accepted an out-of-range value")
+	}
+}
+
+func TestLookupValidatorRegexp(t *testing.T) {
+	v, err := lookupValidator("regexp:^[a-z]+$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v("abc"); err != nil {
+		t.Errorf("regexp validator rejected a matching value: %v", err)
+	}
+	if err := v("ABC"); err == nil {
+		t.Error("regexp validator accepted a non-matching value")
+	}
+}
+
+func TestAskFieldRejectsUnsupportedSliceKind(t *testing.T) {
+	q := NewQuestion()
+
+	_, _, err := q.askField(fieldSchema{prompt: "Ports"}, reflect.TypeOf([]int{}))
+	if _, ok := err.(UnsupportedKindError); !ok {
+		t.Errorf("askField with []int = %v, want UnsupportedKindError", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// tty is the single shared handle to the controlling terminal, toggled
+// into and out of raw mode by Line.Read and Question.getLine's callers.
+var tty = &termState{}
+
+type termState struct {
+	saved    syscall.Termios
+	hasSaved bool
+	depth    int // number of unmatched RawMode calls; Restore only acts at 0
+}
+
+// RawMode puts stdin into raw (non-canonical, no-echo) mode, remembering
+// the previous settings so Restore can put them back. It returns an error
+// when stdin is not a terminal, e.g. because it's a pipe, so callers can
+// fall back to a plain bufio read.
+//
+// RawMode is reentrant: a call made while stdin is already raw (e.g. a
+// Line.Read started from inside AskSelect's filter prompt) just bumps a
+// nesting count instead of re-saving the already-raw settings, so the
+// matching Restore calls unwind back to the real cooked settings instead
+// of clobbering them partway through.
+func (t *termState) RawMode() error {
+	if t.depth > 0 {
+		t.depth++
+		return nil
+	}
+
+	fd := int(os.Stdin.Fd())
+
+	var st syscall.Termios
+	if err := tcGetAttr(fd, &st); err != nil {
+		return err
+	}
+	t.saved = st
+	t.hasSaved = true
+
+	raw := st
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Iflag &^= syscall.IXON | syscall.ICRNL | syscall.BRKINT | syscall.INPCK | syscall.ISTRIP
+	raw.Oflag &^= syscall.OPOST
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := tcSetAttr(fd, &raw); err != nil {
+		return err
+	}
+	t.depth++
+	return nil
+}
+
+// Restore puts stdin back into the mode it was in before the outermost
+// RawMode call. It is a no-op if RawMode was never called or failed, and,
+// for nested RawMode calls, until the matching outer Restore is reached.
+func (t *termState) Restore() {
+	if t.depth == 0 {
+		return
+	}
+	t.depth--
+	if t.depth > 0 || !t.hasSaved {
+		return
+	}
+	tcSetAttr(int(os.Stdin.Fd()), &t.saved)
+}
+
+// IsTerminal reports whether stdin looks like a terminal, without
+// changing its mode.
+func (t *termState) IsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func tcGetAttr(fd int, st *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(st)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func tcSetAttr(fd int, st *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(st)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2010  The "go-linoise" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package linoise
+
+import (
+	"syscall"
+	"testing"
+)
+
+// TestTermStateRawModeIsReentrant guards against a nested RawMode call
+// (e.g. Line.Read started from AskSelect's "/" filter prompt while
+// AskSelect already holds raw mode) re-saving the already-raw settings
+// and having its Restore clobber the real cooked settings the outer
+// RawMode call is still relying on.
+func TestTermStateRawModeIsReentrant(t *testing.T) {
+	cooked := syscall.Termios{Iflag: 0x1234}
+	ts := &termState{saved: cooked, hasSaved: true, depth: 1}
+
+	if err := ts.RawMode(); err != nil {
+		t.Fatalf("nested RawMode returned an error: %v", err)
+	}
+	if ts.depth != 2 {
+		t.Fatalf("depth after nested RawMode = %d, want 2", ts.depth)
+	}
+	if ts.saved != cooked {
+		t.Fatalf("nested RawMode overwrote the outer call's saved settings: %+v", ts.saved)
+	}
+
+	ts.Restore()
+	if ts.depth != 1 {
+		t.Fatalf("depth after inner Restore = %d, want 1 (outer call still active)", ts.depth)
+	}
+	if ts.saved != cooked {
+		t.Fatalf("inner Restore should not have touched saved settings: %+v", ts.saved)
+	}
+}
+
+func TestTermStateRestoreWithoutRawModeIsNoop(t *testing.T) {
+	ts := &termState{}
+	ts.Restore() // must not panic or misbehave
+	if ts.depth != 0 {
+		t.Errorf("depth = %d, want 0", ts.depth)
+	}
+}